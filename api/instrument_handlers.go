@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"nofx/market"
+
+	"github.com/gin-gonic/gin"
+)
+
+// instrumentCacheTTL 合约规格变化很慢，缓存时间远长于K线缓存
+const instrumentCacheTTL = 30 * time.Minute
+
+type instrumentCacheEntry struct {
+	expiresAt time.Time
+	info      market.InstrumentInfo
+}
+
+type instrumentCache struct {
+	ttl  time.Duration
+	mu   sync.RWMutex
+	data map[string]instrumentCacheEntry
+}
+
+func newInstrumentCache(ttl time.Duration) *instrumentCache {
+	return &instrumentCache{
+		ttl:  ttl,
+		data: make(map[string]instrumentCacheEntry),
+	}
+}
+
+func (c *instrumentCache) Get(key string) (market.InstrumentInfo, bool) {
+	if c == nil {
+		return market.InstrumentInfo{}, false
+	}
+	c.mu.RLock()
+	entry, ok := c.data[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		return market.InstrumentInfo{}, false
+	}
+	return entry.info, true
+}
+
+func (c *instrumentCache) Set(key string, info market.InstrumentInfo) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.data[key] = instrumentCacheEntry{
+		expiresAt: time.Now().Add(c.ttl),
+		info:      info,
+	}
+	c.mu.Unlock()
+}
+
+// handleMarketInstrument 返回交易对的tick size、合约面值等规格，供客户端校验下单精度是否合法
+func (s *Server) handleMarketInstrument(c *gin.Context) {
+	symbol := c.DefaultQuery("symbol", "SOL")
+
+	info, cached := s.lookupInstrument(symbol)
+	if !cached {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("获取合约规格失败: %s", symbol)})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// lookupInstrument 优先读取缓存，未命中时回源拉取，用于buildTradeEvents的精度取整。
+// 缓存key统一用market.Normalize归一化，避免"SOL"/"SOLUSDT"等同一交易对的不同写法各自落盘、互不命中
+func (s *Server) lookupInstrument(symbol string) (market.InstrumentInfo, bool) {
+	cacheKey := market.Normalize(symbol)
+	if cached, ok := s.instrumentCache.Get(cacheKey); ok {
+		return cached, true
+	}
+
+	info, err := market.GetInstrumentInfo(symbol)
+	if err != nil {
+		return market.InstrumentInfo{}, false
+	}
+	s.instrumentCache.Set(cacheKey, info)
+	return info, true
+}
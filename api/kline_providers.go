@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+
+	"nofx/market"
+)
+
+// defaultKlineProviders 是未命中路由规则时的故障转移顺序
+func defaultKlineProviders() []market.KlineProvider {
+	return []market.KlineProvider{
+		market.NewBinanceProvider(),
+		market.NewBybitProvider(),
+		market.NewOKXProvider(),
+	}
+}
+
+// resolveKlineProviders 根据symbol路由规则和source覆盖参数，返回按优先级排序的候选数据源
+func (s *Server) resolveKlineProviders(symbol, source string) ([]market.KlineProvider, error) {
+	if source != "" {
+		for _, p := range s.providers {
+			if strings.EqualFold(p.Name(), source) {
+				return []market.KlineProvider{p}, nil
+			}
+		}
+		return nil, fmt.Errorf("未知的数据源: %s", source)
+	}
+
+	preferred := s.providerRouting[strings.ToUpper(symbol)]
+	if preferred == "" {
+		return s.providers, nil
+	}
+
+	ordered := make([]market.KlineProvider, 0, len(s.providers))
+	var rest []market.KlineProvider
+	for _, p := range s.providers {
+		if strings.EqualFold(p.Name(), preferred) {
+			ordered = append(ordered, p)
+		} else {
+			rest = append(rest, p)
+		}
+	}
+	return append(ordered, rest...), nil
+}
+
+// fetchKlinesWithFailover 依次尝试候选数据源，首个返回非空结果的数据源会被采用并标记为source
+func fetchKlinesWithFailover(providers []market.KlineProvider, symbol, interval string, limit int) ([]market.Kline, string, error) {
+	var lastErr error
+	for _, p := range providers {
+		klines, err := p.GetKlines(symbol, interval, limit)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(klines) == 0 {
+			lastErr = fmt.Errorf("%s返回空K线", p.Name())
+			continue
+		}
+		return klines, p.Name(), nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有可用的K线数据源")
+	}
+	return nil, "", lastErr
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -21,6 +23,7 @@ import (
 type klineCacheEntry struct {
 	expiresAt time.Time
 	klines    []market.Kline
+	source    string
 }
 
 type klineCache struct {
@@ -36,22 +39,28 @@ func newKlineCache(ttl time.Duration) *klineCache {
 	}
 }
 
-func (c *klineCache) Get(key string) ([]market.Kline, bool) {
+func (c *klineCache) Get(key string) ([]market.Kline, string, bool) {
 	if c == nil {
-		return nil, false
+		return nil, "", false
 	}
 	c.mu.RLock()
 	entry, ok := c.data[key]
 	c.mu.RUnlock()
 	if !ok || time.Now().After(entry.expiresAt) {
-		return nil, false
+		return nil, "", false
 	}
 	result := make([]market.Kline, len(entry.klines))
 	copy(result, entry.klines)
-	return result, true
+	return result, entry.source, true
 }
 
-func (c *klineCache) Set(key string, klines []market.Kline) {
+// klineCacheKey 构造缓存键，source留空表示未指定数据源，必须与hydrateSubscriber共用以保证hydrate能命中REST写入的缓存
+func klineCacheKey(symbol, interval string, limit int, source string) string {
+	return strings.ToUpper(symbol) + "|" + strings.ToLower(interval) + "|" + strconv.Itoa(limit) + "|" + strings.ToLower(source)
+}
+
+// Set 缓存K线数据，source记录实际命中的数据源，使缓存命中的响应也能如实报告来源
+func (c *klineCache) Set(key string, klines []market.Kline, source string) {
 	if c == nil {
 		return
 	}
@@ -59,6 +68,7 @@ func (c *klineCache) Set(key string, klines []market.Kline) {
 	c.data[key] = klineCacheEntry{
 		expiresAt: time.Now().Add(c.ttl),
 		klines:    append([]market.Kline(nil), klines...),
+		source:    source,
 	}
 	c.mu.Unlock()
 }
@@ -106,30 +116,42 @@ type klineDTO struct {
 	Volume    float64 `json:"volume"`
 }
 
-// handleMarketKline 返回K线数据（带缓存）
+type klineResponse struct {
+	Source string     `json:"source"`
+	Klines []klineDTO `json:"klines"`
+}
+
+// handleMarketKline 返回K线数据（带缓存），支持通过source参数强制指定数据源并在多数据源间自动故障转移
 func (s *Server) handleMarketKline(c *gin.Context) {
 	symbol := c.DefaultQuery("symbol", "SOL")
 	interval := c.DefaultQuery("interval", "3m")
+	source := strings.ToLower(strings.TrimSpace(c.Query("source")))
 	limit := parseLimit(c.Query("limit"), 500, 1500)
 	if limit <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "limit 参数必须为正整数"})
 		return
 	}
 
-	cacheKey := strings.ToUpper(symbol) + "|" + strings.ToLower(interval) + "|" + strconv.Itoa(limit)
-	if cached, ok := s.klineCache.Get(cacheKey); ok {
-		c.JSON(http.StatusOK, adaptKlines(cached))
+	cacheKey := klineCacheKey(symbol, interval, limit, source)
+	if cached, cachedSource, ok := s.klineCache.Get(cacheKey); ok {
+		c.JSON(http.StatusOK, klineResponse{Source: cachedSource, Klines: adaptKlines(cached)})
 		return
 	}
 
-	klines, err := market.GetKlines(symbol, interval, limit)
+	providers, err := s.resolveKlineProviders(symbol, source)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	klines, usedSource, err := fetchKlinesWithFailover(providers, symbol, interval, limit)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("获取K线失败: %v", err)})
 		return
 	}
 
-	s.klineCache.Set(cacheKey, klines)
-	c.JSON(http.StatusOK, adaptKlines(klines))
+	s.klineCache.Set(cacheKey, klines, usedSource)
+	c.JSON(http.StatusOK, klineResponse{Source: usedSource, Klines: adaptKlines(klines)})
 }
 
 func adaptKlines(klines []market.Kline) []klineDTO {
@@ -195,6 +217,7 @@ func (s *Server) handleAISignals(c *gin.Context) {
 		return
 	}
 
+	s.dispatchAISignals(signals)
 	c.JSON(http.StatusOK, signals)
 }
 
@@ -237,47 +260,63 @@ func (s *Server) handleTrades(c *gin.Context) {
 		return
 	}
 
-	events := buildTradeEvents(records, normalizedSymbol, fromTime, toTime)
+	instrument, _ := s.lookupInstrument(normalizedSymbol)
+	events := buildTradeEvents(records, normalizedSymbol, fromTime, toTime, instrument)
 	if limit > 0 && len(events) > limit {
 		// 只保留最新的 limit 条事件
 		events = events[len(events)-limit:]
 	}
 
+	// 通知在DecisionRecord落盘时（publishTradeEvents）触发一次，这里只读不应重复推送
 	c.JSON(http.StatusOK, events)
 }
 
 type tradeEvent struct {
-	Symbol      string     `json:"symbol"`
-	Side        string     `json:"side"`
-	Action      string     `json:"action"`
-	Timestamp   time.Time  `json:"timestamp"`
-	Price       float64    `json:"price"`
-	Quantity    float64    `json:"quantity"`
-	Leverage    int        `json:"leverage"`
-	Confidence  int        `json:"confidence"`
-	CycleNumber int        `json:"cycle_number"`
-	PnL         *float64   `json:"pnl,omitempty"`
-	PnLPct      *float64   `json:"pnl_pct,omitempty"`
-	Duration    *string    `json:"duration,omitempty"`
-}
-
-type openPositionSnapshot struct {
-	Price     float64
+	Symbol      string       `json:"symbol"`
+	Side        string       `json:"side"`
+	Action      string       `json:"action"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Price       float64      `json:"price"`
+	Quantity    float64      `json:"quantity"`
+	Leverage    int          `json:"leverage"`
+	Confidence  int          `json:"confidence"`
+	CycleNumber int          `json:"cycle_number"`
+	PnL         *float64     `json:"pnl,omitempty"`
+	PnLPct      *float64     `json:"pnl_pct,omitempty"`
+	Duration    *string      `json:"duration,omitempty"`
+	MatchedLots []matchedLot `json:"matched_lots,omitempty"`
+}
+
+// matchedLot 描述一次平仓消耗了哪一笔历史开仓及其贡献的盈亏
+type matchedLot struct {
+	Quantity  float64   `json:"quantity"`
+	Price     float64   `json:"price"`
+	Leverage  int       `json:"leverage"`
+	Timestamp time.Time `json:"timestamp"`
+	PnL       float64   `json:"pnl"`
+}
+
+// lot 是一笔尚未被完全平仓消耗的开仓记录，用于FIFO匹配金字塔加仓/部分平仓
+type lot struct {
 	Quantity  float64
+	Price     float64
 	Leverage  int
 	Timestamp time.Time
 }
 
-func buildTradeEvents(records []*logger.DecisionRecord, symbol string, from, to *time.Time) []tradeEvent {
+// lotQtyEpsilon 用于浮点数量比较，避免舍入误差导致lot无法被判定为耗尽
+const lotQtyEpsilon = 1e-8
+
+func buildTradeEvents(records []*logger.DecisionRecord, symbol string, from, to *time.Time, instrument market.InstrumentInfo) []tradeEvent {
 	if len(records) == 0 {
 		return []tradeEvent{}
 	}
 	filtered := make([]tradeEvent, 0, len(records))
-	openPositions := make(map[string]openPositionSnapshot)
+	openPositions := make(map[string][]*lot)
 
 	for _, record := range records {
 		for _, action := range record.Decisions {
-			if !strings.EqualFold(action.Symbol, symbol) {
+			if symbol != "" && !strings.EqualFold(action.Symbol, symbol) {
 				continue
 			}
 
@@ -304,8 +343,8 @@ func buildTradeEvents(records []*logger.DecisionRecord, symbol string, from, to
 				Side:        side,
 				Action:      action.Action,
 				Timestamp:   actionTime,
-				Price:       action.Price,
-				Quantity:    action.Quantity,
+				Price:       market.RoundToTick(action.Price, instrument.PriceTickSize),
+				Quantity:    market.RoundToTick(action.Quantity, instrument.AmountTickSize),
 				Leverage:    action.Leverage,
 				Confidence:  action.Confidence,
 				CycleNumber: record.CycleNumber,
@@ -314,45 +353,19 @@ func buildTradeEvents(records []*logger.DecisionRecord, symbol string, from, to
 
 			switch action.Action {
 			case "open_long", "open_short":
-				if event.Quantity <= 0 {
-					// 若日志缺少数量，跳过该开仓以免影响匹配
-					continue
-				}
-				openPositions[posKey] = openPositionSnapshot{
-					Price:     event.Price,
-					Quantity:  event.Quantity,
-					Leverage:  maxInt(event.Leverage, 1),
-					Timestamp: event.Timestamp,
-				}
+				openPositions[posKey] = appendOrFillLot(openPositions[posKey], event)
 				filtered = append(filtered, event)
 
 			case "close_long", "close_short":
-				openPos, ok := openPositions[posKey]
-				if ok {
-					if event.Quantity <= 0 {
-						event.Quantity = openPos.Quantity
-					}
-					marginUsed := (openPos.Quantity * openPos.Price) / float64(maxInt(openPos.Leverage, 1))
-					var pnl float64
-					if side == "long" {
-						pnl = openPos.Quantity * (event.Price - openPos.Price)
-					} else {
-						pnl = openPos.Quantity * (openPos.Price - event.Price)
+				lots := openPositions[posKey]
+				matched, remainingQty, closeQty := matchLotsFIFO(lots, event, side)
+				if len(matched) > 0 {
+					if remainingQty > lotQtyEpsilon {
+						log.Printf("trades: %s 平仓数量 %.8f 超出可平数量 %.8f，已按实际持仓数量结算", posKey, event.Quantity, closeQty)
 					}
-
-					pnlPct := 0.0
-					if marginUsed > 0 {
-						pnlPct = (pnl / marginUsed) * 100
-					}
-
-					duration := event.Timestamp.Sub(openPos.Timestamp).Round(time.Second).String()
-					eventPnL := pnl
-					eventPnLPct := pnlPct
-					eventDuration := duration
-					event.PnL = &eventPnL
-					event.PnLPct = &eventPnLPct
-					event.Duration = &eventDuration
-					delete(openPositions, posKey)
+					event.Quantity = closeQty
+					event.PnL, event.PnLPct, event.Duration, event.MatchedLots = summarizeMatchedLots(matched, event.Timestamp)
+					openPositions[posKey] = trimExhaustedLots(lots)
 				}
 				filtered = append(filtered, event)
 			}
@@ -373,6 +386,115 @@ func sideFromAction(action string) string {
 	}
 }
 
+// appendOrFillLot 把一次开仓事件计入FIFO队列；若队列中已有同价位的待定（数量为0）lot，
+// 说明该开仓此前因日志缺数量而被记为占位，这里用最新数量回填而不是重复入队
+func appendOrFillLot(lots []*lot, event tradeEvent) []*lot {
+	for _, l := range lots {
+		if l.Quantity <= lotQtyEpsilon && floatsEqual(l.Price, event.Price) {
+			l.Quantity = event.Quantity
+			l.Leverage = maxInt(event.Leverage, 1)
+			l.Timestamp = event.Timestamp
+			return lots
+		}
+	}
+	return append(lots, &lot{
+		Quantity:  event.Quantity,
+		Price:     event.Price,
+		Leverage:  maxInt(event.Leverage, 1),
+		Timestamp: event.Timestamp,
+	})
+}
+
+// matchLotsFIFO 按先进先出消费lots直至平仓数量耗尽，必要时拆分最后一笔lot。
+// 平仓数量缺失（<=0）时视为清仓，按当前所有lot的总数量结算。
+// 返回值：matched为本次消耗的lot明细，remaining为未能匹配到持仓的剩余数量，closeQty为实际成交数量。
+func matchLotsFIFO(lots []*lot, event tradeEvent, side string) (matched []matchedLot, remaining float64, closeQty float64) {
+	remaining = event.Quantity
+	if remaining <= lotQtyEpsilon {
+		for _, l := range lots {
+			remaining += l.Quantity
+		}
+	}
+	if remaining <= lotQtyEpsilon {
+		return nil, 0, 0
+	}
+
+	matched = make([]matchedLot, 0, len(lots))
+	for _, l := range lots {
+		if remaining <= lotQtyEpsilon {
+			break
+		}
+		if l.Quantity <= lotQtyEpsilon {
+			continue
+		}
+
+		qty := l.Quantity
+		if qty > remaining {
+			qty = remaining
+		}
+
+		var pnl float64
+		if side == "long" {
+			pnl = qty * (event.Price - l.Price)
+		} else {
+			pnl = qty * (l.Price - event.Price)
+		}
+
+		matched = append(matched, matchedLot{
+			Quantity:  qty,
+			Price:     l.Price,
+			Leverage:  l.Leverage,
+			Timestamp: l.Timestamp,
+			PnL:       pnl,
+		})
+
+		l.Quantity -= qty
+		remaining -= qty
+		closeQty += qty
+	}
+
+	return matched, remaining, closeQty
+}
+
+// trimExhaustedLots 移除已被完全消耗的lot，保留仍有剩余数量的队列顺序
+func trimExhaustedLots(lots []*lot) []*lot {
+	remaining := make([]*lot, 0, len(lots))
+	for _, l := range lots {
+		if l.Quantity > lotQtyEpsilon {
+			remaining = append(remaining, l)
+		}
+	}
+	return remaining
+}
+
+// summarizeMatchedLots 汇总FIFO匹配出的lot明细为已实现盈亏、盈亏百分比与加权平均持仓时长
+func summarizeMatchedLots(matched []matchedLot, closeTime time.Time) (*float64, *float64, *string, []matchedLot) {
+	var totalPnL, totalMargin, totalQty, weightedDurationNs float64
+	for _, m := range matched {
+		totalPnL += m.PnL
+		totalMargin += (m.Quantity * m.Price) / float64(maxInt(m.Leverage, 1))
+		totalQty += m.Quantity
+		weightedDurationNs += m.Quantity * float64(closeTime.Sub(m.Timestamp))
+	}
+
+	pnlPct := 0.0
+	if totalMargin > 0 {
+		pnlPct = (totalPnL / totalMargin) * 100
+	}
+
+	avgDuration := time.Duration(0)
+	if totalQty > 0 {
+		avgDuration = time.Duration(weightedDurationNs / totalQty).Round(time.Second)
+	}
+	durationStr := avgDuration.String()
+
+	return &totalPnL, &pnlPct, &durationStr, matched
+}
+
+func floatsEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
 func parseLimit(value string, defaultValue, maxValue int) int {
 	if value == "" {
 		return defaultValue
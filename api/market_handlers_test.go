@@ -0,0 +1,122 @@
+package api
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"nofx/logger"
+	"nofx/market"
+)
+
+func decisionRecord(cycle int, ts time.Time, actions ...logger.DecisionAction) *logger.DecisionRecord {
+	return &logger.DecisionRecord{
+		CycleNumber: cycle,
+		Timestamp:   ts,
+		Decisions:   actions,
+	}
+}
+
+func openAction(symbol, action string, price, qty float64, leverage int, ts time.Time) logger.DecisionAction {
+	return logger.DecisionAction{
+		Symbol:     symbol,
+		Action:     action,
+		Price:      price,
+		Quantity:   qty,
+		Leverage:   leverage,
+		Confidence: 80,
+		Timestamp:  ts,
+	}
+}
+
+func almostEqual(t *testing.T, got, want float64, msg string) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-6 {
+		t.Fatalf("%s: got %v, want %v", msg, got, want)
+	}
+}
+
+func TestBuildTradeEvents_SingleOpenClose(t *testing.T) {
+	base := time.Now().UTC()
+	records := []*logger.DecisionRecord{
+		decisionRecord(1, base, openAction("SOL", "open_long", 100, 10, 2, base)),
+		decisionRecord(2, base.Add(time.Minute), openAction("SOL", "close_long", 110, 10, 2, base.Add(time.Minute))),
+	}
+
+	events := buildTradeEvents(records, "SOL", nil, nil, market.InstrumentInfo{})
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	closeEvent := events[1]
+	if closeEvent.PnL == nil {
+		t.Fatalf("expected PnL to be set on close event")
+	}
+	almostEqual(t, *closeEvent.PnL, 100, "single open/close pnl")
+	if len(closeEvent.MatchedLots) != 1 {
+		t.Fatalf("expected 1 matched lot, got %d", len(closeEvent.MatchedLots))
+	}
+}
+
+func TestBuildTradeEvents_PyramidedOpenClosedInOneShot(t *testing.T) {
+	base := time.Now().UTC()
+	records := []*logger.DecisionRecord{
+		decisionRecord(1, base, openAction("SOL", "open_long", 100, 5, 2, base)),
+		decisionRecord(2, base.Add(time.Minute), openAction("SOL", "open_long", 120, 5, 2, base.Add(time.Minute))),
+		decisionRecord(3, base.Add(2*time.Minute), openAction("SOL", "close_long", 130, 10, 2, base.Add(2*time.Minute))),
+	}
+
+	events := buildTradeEvents(records, "SOL", nil, nil, market.InstrumentInfo{})
+	closeEvent := events[len(events)-1]
+	if len(closeEvent.MatchedLots) != 2 {
+		t.Fatalf("expected 2 matched lots, got %d", len(closeEvent.MatchedLots))
+	}
+	// (130-100)*5 + (130-120)*5 = 150 + 50 = 200
+	almostEqual(t, *closeEvent.PnL, 200, "pyramided close pnl")
+}
+
+func TestBuildTradeEvents_PartialCloseLeavesResidual(t *testing.T) {
+	base := time.Now().UTC()
+	records := []*logger.DecisionRecord{
+		decisionRecord(1, base, openAction("SOL", "open_long", 100, 10, 1, base)),
+		decisionRecord(2, base.Add(time.Minute), openAction("SOL", "close_long", 110, 4, 1, base.Add(time.Minute))),
+		decisionRecord(3, base.Add(2*time.Minute), openAction("SOL", "close_long", 120, 6, 1, base.Add(2*time.Minute))),
+	}
+
+	events := buildTradeEvents(records, "SOL", nil, nil, market.InstrumentInfo{})
+
+	firstClose := events[1]
+	almostEqual(t, firstClose.Quantity, 4, "partial close quantity")
+	almostEqual(t, *firstClose.PnL, 40, "partial close pnl")
+
+	secondClose := events[2]
+	almostEqual(t, secondClose.Quantity, 6, "residual close quantity")
+	almostEqual(t, *secondClose.PnL, 120, "residual close pnl")
+}
+
+func TestBuildTradeEvents_CloseLargerThanOpenClamps(t *testing.T) {
+	base := time.Now().UTC()
+	records := []*logger.DecisionRecord{
+		decisionRecord(1, base, openAction("SOL", "open_long", 100, 5, 1, base)),
+		decisionRecord(2, base.Add(time.Minute), openAction("SOL", "close_long", 110, 20, 1, base.Add(time.Minute))),
+	}
+
+	events := buildTradeEvents(records, "SOL", nil, nil, market.InstrumentInfo{})
+	closeEvent := events[1]
+	almostEqual(t, closeEvent.Quantity, 5, "clamped close quantity")
+	almostEqual(t, *closeEvent.PnL, 50, "clamped close pnl")
+}
+
+func TestBuildTradeEvents_ZeroQtyOpenBackfilledBySubsequentRecord(t *testing.T) {
+	base := time.Now().UTC()
+	records := []*logger.DecisionRecord{
+		decisionRecord(1, base, openAction("SOL", "open_long", 100, 0, 1, base)),
+		decisionRecord(2, base.Add(30*time.Second), openAction("SOL", "open_long", 100, 8, 1, base.Add(30*time.Second))),
+		decisionRecord(3, base.Add(time.Minute), openAction("SOL", "close_long", 110, 8, 1, base.Add(time.Minute))),
+	}
+
+	events := buildTradeEvents(records, "SOL", nil, nil, market.InstrumentInfo{})
+	closeEvent := events[len(events)-1]
+	almostEqual(t, closeEvent.Quantity, 8, "backfilled open close quantity")
+	almostEqual(t, *closeEvent.PnL, 80, "backfilled open close pnl")
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// marketStream*Interval 分别是K线、AI信号、决策记录的轮询节奏；AI信号和Supabase变更流本质上也是轮询同一张表，
+// 轮询间隔短于K线缓存TTL即可保证WS订阅者拿到的数据不比REST轮询慢
+const (
+	marketStreamKlineInterval  = 5 * time.Second
+	marketStreamSignalInterval = 10 * time.Second
+	marketStreamTradeInterval  = 3 * time.Second
+)
+
+// streamState 记录各个轮询源的"水位线"，避免同一条K线/信号/决策被重复推送
+type streamState struct {
+	mu           sync.Mutex
+	lastOpenTime map[string]int64 // key: symbol|interval
+	lastSignalAt time.Time        // 最近一次已推送的AI信号created_at
+	lastCycle    map[string]int   // key: traderID，最近一次已推送的CycleNumber
+}
+
+func newStreamState() *streamState {
+	return &streamState{
+		lastOpenTime: make(map[string]int64),
+		lastCycle:    make(map[string]int),
+	}
+}
+
+// StartMarketStreams 启动后台扇出：K线增量、AI信号变更流、AI/手动DecisionRecord增量，
+// 替代客户端对 handleMarketKline/handleAISignals/handleTrades 的轮询。
+// symbols为需要持续推送K线的交易对，traderIDs为需要持续推送交易事件的trader。
+// 应在Server启动时与hub.run()一起以goroutine方式调用。
+func (s *Server) StartMarketStreams(ctx context.Context, symbols []string, interval string, traderIDs []string) {
+	state := newStreamState()
+
+	go s.streamKlines(ctx, state, symbols, interval)
+	go s.streamAISignals(ctx, state)
+	go s.streamDecisionRecords(ctx, state, traderIDs)
+}
+
+func (s *Server) streamKlines(ctx context.Context, state *streamState, symbols []string, interval string) {
+	ticker := time.NewTicker(marketStreamKlineInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, symbol := range symbols {
+				s.pollKline(state, symbol, interval)
+			}
+		}
+	}
+}
+
+func (s *Server) pollKline(state *streamState, symbol, interval string) {
+	providers, err := s.resolveKlineProviders(symbol, "")
+	if err != nil {
+		log.Printf("stream: 解析%s的K线数据源失败: %v", symbol, err)
+		return
+	}
+
+	klines, _, err := fetchKlinesWithFailover(providers, symbol, interval, 2)
+	if err != nil || len(klines) == 0 {
+		if err != nil {
+			log.Printf("stream: 拉取%s K线失败: %v", symbol, err)
+		}
+		return
+	}
+
+	latest := klines[len(klines)-1]
+	key := symbol + "|" + interval
+
+	state.mu.Lock()
+	seen := state.lastOpenTime[key]
+	isNew := latest.OpenTime > seen
+	if isNew {
+		state.lastOpenTime[key] = latest.OpenTime
+	}
+	state.mu.Unlock()
+
+	if isNew {
+		s.publishKline(symbol, interval, latest)
+	}
+}
+
+func (s *Server) streamAISignals(ctx context.Context, state *streamState) {
+	if s.supabase == nil {
+		return
+	}
+
+	ticker := time.NewTicker(marketStreamSignalInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.pollAISignals(state)
+		}
+	}
+}
+
+func (s *Server) pollAISignals(state *streamState) {
+	state.mu.Lock()
+	since := state.lastSignalAt
+	state.mu.Unlock()
+
+	var sincePtr *time.Time
+	if !since.IsZero() {
+		sincePtr = &since
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	signals, err := s.supabase.fetchAISignals(ctx, "", sincePtr, 100)
+	if err != nil {
+		log.Printf("stream: 拉取AI信号失败: %v", err)
+		return
+	}
+	if len(signals) == 0 {
+		return
+	}
+
+	latest := since
+	fresh := make([]AISignalResponse, 0, len(signals))
+	for _, signal := range signals {
+		// created_at按gte过滤，水位线上已推送过的信号每轮都会再次返回，这里跳过
+		if !since.IsZero() && !signal.Timestamp.After(since) {
+			continue
+		}
+		fresh = append(fresh, signal)
+		if signal.Timestamp.After(latest) {
+			latest = signal.Timestamp
+		}
+		s.hub.publish("ai_signal", "", "", signal)
+	}
+
+	state.mu.Lock()
+	state.lastSignalAt = latest
+	state.mu.Unlock()
+
+	s.dispatchAISignals(fresh)
+}
+
+func (s *Server) streamDecisionRecords(ctx context.Context, state *streamState, traderIDs []string) {
+	if s.traderManager == nil {
+		return
+	}
+
+	ticker := time.NewTicker(marketStreamTradeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, traderID := range traderIDs {
+				s.pollDecisionRecords(state, traderID)
+			}
+		}
+	}
+}
+
+func (s *Server) pollDecisionRecords(state *streamState, traderID string) {
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		return
+	}
+
+	records, err := trader.GetDecisionLogger().GetLatestRecords(50)
+	if err != nil {
+		log.Printf("stream: 拉取trader %s 决策记录失败: %v", traderID, err)
+		return
+	}
+
+	state.mu.Lock()
+	lastCycle := state.lastCycle[traderID]
+	state.mu.Unlock()
+
+	maxCycle := lastCycle
+	for _, record := range records {
+		if record.CycleNumber <= lastCycle {
+			continue
+		}
+		// AI决策循环落盘的记录在此被首次发现并推送，手动操作已在recordManualAction中即时推送，
+		// 这里天然幂等：下一轮水位线更新后不会再次匹配到同一cycle
+		s.publishTradeEvents(record)
+		if record.CycleNumber > maxCycle {
+			maxCycle = record.CycleNumber
+		}
+	}
+
+	if maxCycle > lastCycle {
+		state.mu.Lock()
+		state.lastCycle[traderID] = maxCycle
+		state.mu.Unlock()
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/notify"
+)
+
+// notifyRule 描述一条推送路由规则：symbol/trader/eventType/assets留空表示不限制
+type notifyRule struct {
+	Symbol        string
+	TraderID      string
+	EventType     string
+	Assets        []string
+	MinConfidence float64
+}
+
+func (r notifyRule) matchesSignal(signal AISignalResponse) bool {
+	if r.MinConfidence > 0 && signal.Confidence < r.MinConfidence {
+		return false
+	}
+	if r.EventType != "" && !strings.EqualFold(r.EventType, signal.EventType) {
+		return false
+	}
+	if len(r.Assets) > 0 && !matchSymbol(r.Assets, signal.Assets, signal.AssetNames) {
+		return false
+	}
+	return true
+}
+
+// dedupTTL 是dedupSet记录的最长保留时间，超过后允许同一key重新推送并从map中清除，
+// 避免长期运行的进程里seen无限增长
+const dedupTTL = 24 * time.Hour
+
+// dedupSet 按key记录已推送过的通知，避免重复轮询导致同一事件被多次推送。
+// 仅在内存中维护，进程重启后该保证不成立——streamAISignals重启时lastSignalAt和这里的seen
+// 会一起清零，重启后拉取到的历史信号仍会重新推送一次；这里只覆盖同一进程生命周期内的去重。
+type dedupSet struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+func newDedupSet() *dedupSet {
+	return &dedupSet{seen: make(map[string]time.Time)}
+}
+
+// markIfNew 返回true表示该key此前未推送过；顺带清理超过dedupTTL的旧记录，防止seen无限增长
+func (d *dedupSet) markIfNew(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pruneLocked()
+	if _, ok := d.seen[key]; ok {
+		return false
+	}
+	d.seen[key] = time.Now()
+	return true
+}
+
+func (d *dedupSet) pruneLocked() {
+	cutoff := time.Now().Add(-dedupTTL)
+	for key, seenAt := range d.seen {
+		if seenAt.Before(cutoff) {
+			delete(d.seen, key)
+		}
+	}
+}
+
+// dispatchAISignals 异步推送满足路由规则的高置信度信号，失败仅记录日志不影响接口响应
+func (s *Server) dispatchAISignals(signals []AISignalResponse) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+	for _, signal := range signals {
+		signal := signal
+		if !s.shouldNotifySignal(signal) {
+			continue
+		}
+		dedupKey := signal.ModelName + "|" + signal.Timestamp.UTC().Format(time.RFC3339Nano)
+		if !s.signalDedup.markIfNew(dedupKey) {
+			continue
+		}
+
+		go func() {
+			msg := notify.Message{
+				Title: fmt.Sprintf("[AI信号] %s %s", signal.Direction, strings.Join(signal.Assets, ",")),
+				Text:  signal.SummaryCN,
+				Fields: map[string]string{
+					"confidence": fmt.Sprintf("%.2f", signal.Confidence),
+					"event_type": signal.EventType,
+				},
+			}
+			s.notify(msg)
+		}()
+	}
+}
+
+func (s *Server) shouldNotifySignal(signal AISignalResponse) bool {
+	if len(s.notifyRules) == 0 {
+		return false
+	}
+	for _, rule := range s.notifyRules {
+		if rule.matchesSignal(signal) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyTradeEvent 推送平仓事件，携带symbol/side/价格；event带有PnL/PnLPct/Duration时一并附上，
+// 缺失时消息中没有这几项（publishTradeEvents构建的平仓事件只有在同一条record内匹配到开仓时才带这些值）。
+// 只应在DecisionRecord落盘时（见publishTradeEvents）调用一次，而不是从被轮询的读接口调用，
+// 因此这里按symbol+side+timestamp去重，防止同一笔平仓因重复append或重试而被多次推送。
+func (s *Server) notifyTradeEvent(event tradeEvent) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+	if event.Action != "close_long" && event.Action != "close_short" {
+		return
+	}
+
+	dedupKey := event.Symbol + "|" + event.Side + "|" + event.Timestamp.UTC().Format(time.RFC3339Nano)
+	if !s.tradeDedup.markIfNew(dedupKey) {
+		return
+	}
+
+	fields := map[string]string{
+		"symbol": event.Symbol,
+		"side":   event.Side,
+		"price":  fmt.Sprintf("%.6f", event.Price),
+	}
+	if event.PnL != nil {
+		fields["pnl"] = fmt.Sprintf("%.4f", *event.PnL)
+	}
+	if event.PnLPct != nil {
+		fields["pnl_pct"] = fmt.Sprintf("%.2f%%", *event.PnLPct)
+	}
+	if event.Duration != nil {
+		fields["duration"] = *event.Duration
+	}
+
+	go s.notify(notify.Message{
+		Title:  fmt.Sprintf("[平仓] %s %s", event.Symbol, event.Side),
+		Fields: fields,
+	})
+}
+
+// notify 依次向所有已配置的渠道推送（共用同一个5秒超时），单个渠道失败只记录日志
+func (s *Server) notify(msg notify.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, n := range s.notifiers {
+		if err := n.Notify(ctx, msg); err != nil {
+			log.Printf("notify: %s推送失败: %v", n.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"nofx/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 签名相关header名沿用bybit签名方案，便于已有客户端直接复用签名逻辑
+const (
+	headerAPIKey     = "X-API-KEY"
+	headerTimestamp  = "X-TIMESTAMP"
+	headerRecvWindow = "X-RECV-WINDOW"
+	headerSign       = "X-SIGN"
+)
+
+// maxRecvWindowMs 是客户端可声明的最大接收窗口，超过则拒绝——
+// X-RECV-WINDOW由客户端提供，不设上限会让时间戳校验形同虚设（客户端可声明任意大的窗口使签名长期可重放）
+const maxRecvWindowMs = 5_000
+
+// requireSignedRequest 校验 X-API-KEY/X-TIMESTAMP/X-RECV-WINDOW/X-SIGN，签名规则为
+// hex(hmac_sha256(secret, timestamp+apiKey+recvWindow+body))，仅应用于新增的私有交易接口
+func (s *Server) requireSignedRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader(headerAPIKey)
+		timestampStr := c.GetHeader(headerTimestamp)
+		recvWindowStr := c.GetHeader(headerRecvWindow)
+		sign := c.GetHeader(headerSign)
+
+		if apiKey == "" || timestampStr == "" || recvWindowStr == "" || sign == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "缺少签名请求头"})
+			return
+		}
+
+		secret, ok := s.traderAPISecrets[apiKey]
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "未知的API KEY"})
+			return
+		}
+
+		timestampMs, err := strconv.ParseInt(timestampStr, 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "X-TIMESTAMP 格式错误"})
+			return
+		}
+		recvWindow, err := strconv.ParseInt(recvWindowStr, 10, 64)
+		if err != nil || recvWindow <= 0 || recvWindow > maxRecvWindowMs {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("X-RECV-WINDOW 必须在(0, %d]毫秒之间", maxRecvWindowMs)})
+			return
+		}
+
+		now := time.Now().UnixMilli()
+		if math.Abs(float64(now-timestampMs)) > float64(recvWindow) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "请求时间戳超出接收窗口"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		payload := timestampStr + apiKey + recvWindowStr + string(body)
+		expected := hmacSHA256Hex(secret, payload)
+		if !hmac.Equal([]byte(expected), []byte(strings.ToLower(sign))) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "签名校验失败"})
+			return
+		}
+
+		c.Set("traderAPIKey", apiKey)
+		c.Next()
+	}
+}
+
+func hmacSHA256Hex(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type traderOrderRequest struct {
+	TraderID string  `json:"trader_id" binding:"required"`
+	Symbol   string  `json:"symbol" binding:"required"`
+	Side     string  `json:"side" binding:"required"`
+	Quantity float64 `json:"quantity" binding:"required"`
+	Price    float64 `json:"price"`
+	Leverage int     `json:"leverage"`
+}
+
+// handleTraderOrder 提交一笔手动下单，写入与AI决策共用的DecisionLogger流，便于UI统一展示
+func (s *Server) handleTraderOrder(c *gin.Context) {
+	var req traderOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(req.TraderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	var openAction string
+	switch {
+	case strings.EqualFold(req.Side, "long"):
+		openAction = "open_long"
+	case strings.EqualFold(req.Side, "short"):
+		openAction = "open_short"
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("side 必须为long或short，收到: %s", req.Side)})
+		return
+	}
+	action := manualAction(openAction, req.Symbol, req.Price, req.Quantity, req.Leverage)
+	if err := s.recordManualAction(trader.GetDecisionLogger(), action); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("记录手动下单失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "accepted"})
+}
+
+type traderCloseRequest struct {
+	TraderID string  `json:"trader_id" binding:"required"`
+	Symbol   string  `json:"symbol" binding:"required"`
+	Side     string  `json:"side" binding:"required"`
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+// handleTraderClose 手动平仓，同样记录进DecisionLogger
+func (s *Server) handleTraderClose(c *gin.Context) {
+	var req traderCloseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(req.TraderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	closeAction := "close_long"
+	if strings.EqualFold(req.Side, "short") {
+		closeAction = "close_short"
+	}
+	action := manualAction(closeAction, req.Symbol, req.Price, req.Quantity, 0)
+	if err := s.recordManualAction(trader.GetDecisionLogger(), action); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("记录手动平仓失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "accepted"})
+}
+
+type traderPauseRequest struct {
+	TraderID string `json:"trader_id" binding:"required"`
+	Paused   bool   `json:"paused"`
+}
+
+// handleTraderPause 暂停/恢复指定trader的自动决策循环
+func (s *Server) handleTraderPause(c *gin.Context) {
+	var req traderPauseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trader, err := s.traderManager.GetTrader(req.TraderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := trader.SetPaused(req.Paused); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("设置暂停状态失败: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "paused": req.Paused})
+}
+
+func manualAction(action, symbol string, price, quantity float64, leverage int) logger.DecisionAction {
+	return logger.DecisionAction{
+		Symbol:     strings.ToUpper(symbol),
+		Action:     strings.ToLower(action),
+		Price:      price,
+		Quantity:   quantity,
+		Leverage:   leverage,
+		Confidence: 100,
+		Timestamp:  time.Now().UTC(),
+	}
+}
+
+// recordManualAction 把人工操作写入trader既有的DecisionLogger流，使UI把手动操作和AI决策展示在同一时间线上
+func (s *Server) recordManualAction(decisionLogger *logger.DecisionLogger, action logger.DecisionAction) error {
+	record := &logger.DecisionRecord{
+		Timestamp: action.Timestamp,
+		Decisions: []logger.DecisionAction{action},
+	}
+	if err := decisionLogger.Append(record); err != nil {
+		return err
+	}
+	s.publishTradeEvents(record)
+	return nil
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/logger"
+	"nofx/market"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPingInterval   = 25 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsClientSendSize = 32
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// 面板与采集端不一定同源，这里放开跨域校验
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsEnvelope 是推送给客户端的统一帧格式
+type wsEnvelope struct {
+	Channel  string      `json:"channel"`
+	Symbol   string      `json:"symbol,omitempty"`
+	Interval string      `json:"interval,omitempty"`
+	Data     interface{} `json:"data"`
+}
+
+// wsSubscription 描述一个客户端关心的频道
+type wsSubscription struct {
+	Channel  string `json:"channel"`
+	Symbol   string `json:"symbol"`
+	Interval string `json:"interval"`
+}
+
+func (sub wsSubscription) key() string {
+	return sub.Channel + "|" + strings.ToUpper(sub.Symbol) + "|" + strings.ToLower(sub.Interval)
+}
+
+type wsClientMsg struct {
+	Type string         `json:"type"`
+	Sub  wsSubscription `json:"subscription"`
+}
+
+// wsClient 代表一个已建立的WebSocket连接
+type wsClient struct {
+	conn *websocket.Conn
+	send chan wsEnvelope
+	hub  *hub
+	mu   sync.RWMutex
+	subs map[string]wsSubscription
+}
+
+func newWsClient(conn *websocket.Conn, h *hub) *wsClient {
+	return &wsClient{
+		conn: conn,
+		send: make(chan wsEnvelope, wsClientSendSize),
+		hub:  h,
+		subs: make(map[string]wsSubscription),
+	}
+}
+
+func (cl *wsClient) subscribed(sub wsSubscription) bool {
+	cl.mu.RLock()
+	defer cl.mu.RUnlock()
+	_, ok := cl.subs[sub.key()]
+	return ok
+}
+
+func (cl *wsClient) subscribe(sub wsSubscription) {
+	cl.mu.Lock()
+	cl.subs[sub.key()] = sub
+	cl.mu.Unlock()
+}
+
+func (cl *wsClient) unsubscribe(sub wsSubscription) {
+	cl.mu.Lock()
+	delete(cl.subs, sub.key())
+	cl.mu.Unlock()
+}
+
+// hub 负责客户端注册、订阅与消息扇出，避免客户端轮询market接口
+type hub struct {
+	mu         sync.RWMutex
+	clients    map[*wsClient]bool
+	register   chan *wsClient
+	unregister chan *wsClient
+	broadcast  chan wsEnvelope
+}
+
+func newHub() *hub {
+	return &hub{
+		clients:    make(map[*wsClient]bool),
+		register:   make(chan *wsClient),
+		unregister: make(chan *wsClient),
+		broadcast:  make(chan wsEnvelope, 256),
+	}
+}
+
+// run 是hub的事件循环，应在Server启动时以goroutine方式运行
+func (h *hub) run() {
+	for {
+		select {
+		case cl := <-h.register:
+			h.mu.Lock()
+			h.clients[cl] = true
+			h.mu.Unlock()
+
+		case cl := <-h.unregister:
+			h.mu.Lock()
+			if _, ok := h.clients[cl]; ok {
+				delete(h.clients, cl)
+				close(cl.send)
+			}
+			h.mu.Unlock()
+
+		case env := <-h.broadcast:
+			h.mu.RLock()
+			for cl := range h.clients {
+				if !cl.subscribed(wsSubscription{Channel: env.Channel, Symbol: env.Symbol, Interval: env.Interval}) {
+					continue
+				}
+				select {
+				case cl.send <- env:
+				default:
+					// 客户端消费跟不上，丢弃该连接避免阻塞扇出
+					go h.kick(cl)
+				}
+			}
+			h.mu.RUnlock()
+		}
+	}
+}
+
+func (h *hub) kick(cl *wsClient) {
+	h.unregister <- cl
+}
+
+// publish 向所有订阅该频道的客户端广播一条消息
+func (h *hub) publish(channel, symbol, interval string, data interface{}) {
+	if h == nil {
+		return
+	}
+	select {
+	case h.broadcast <- wsEnvelope{Channel: channel, Symbol: symbol, Interval: interval, Data: data}:
+	default:
+		log.Printf("ws: broadcast队列已满，丢弃channel=%s symbol=%s", channel, symbol)
+	}
+}
+
+// handleWebSocket 升级连接并处理订阅/心跳，替代 handleMarketKline/handleTrades/handleAISignals 轮询
+func (s *Server) handleWebSocket(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("ws: 升级连接失败: %v", err)
+		return
+	}
+
+	cl := newWsClient(conn, s.hub)
+	s.hub.register <- cl
+
+	go s.wsWritePump(cl)
+	s.wsReadPump(cl)
+}
+
+func (s *Server) wsReadPump(cl *wsClient) {
+	defer func() {
+		s.hub.unregister <- cl
+		cl.conn.Close()
+	}()
+
+	// 仅靠ping/写失败无法探测只收包不应答的半开连接，这里配合wsWritePump的定期ping
+	// 设置读超时并在收到pong时刷新，超时未收到pong即判定对端已失联
+	cl.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	cl.conn.SetPongHandler(func(string) error {
+		cl.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		return nil
+	})
+
+	for {
+		_, raw, err := cl.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var msg wsClientMsg
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+
+		switch msg.Type {
+		case "subscribe":
+			cl.subscribe(msg.Sub)
+			s.hydrateSubscriber(cl, msg.Sub)
+		case "unsubscribe":
+			cl.unsubscribe(msg.Sub)
+		}
+	}
+}
+
+func (s *Server) wsWritePump(cl *wsClient) {
+	ticker := time.NewTicker(wsPingInterval)
+	defer func() {
+		ticker.Stop()
+		cl.conn.Close()
+	}()
+
+	for {
+		select {
+		case env, ok := <-cl.send:
+			cl.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				cl.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := cl.conn.WriteJSON(env); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			cl.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := cl.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// hydrateSubscriber 在新订阅建立时补发最近一批数据，避免客户端等待下一次推送
+func (s *Server) hydrateSubscriber(cl *wsClient, sub wsSubscription) {
+	switch sub.Channel {
+	case "kline":
+		cacheKey := klineCacheKey(sub.Symbol, sub.Interval, 500, "")
+		if cached, _, ok := s.klineCache.Get(cacheKey); ok {
+			select {
+			case cl.send <- wsEnvelope{Channel: "kline", Symbol: sub.Symbol, Interval: sub.Interval, Data: adaptKlines(cached)}:
+			default:
+			}
+		}
+	}
+}
+
+// publishTradeEvents 在DecisionRecord落盘后增量推送 open_long/close_long 等事件给WS订阅者，
+// 并触发平仓通知；这是唯一应该调用notifyTradeEvent的地方——被轮询的handleTrades不应重复通知。
+// 事件只由这一条record构建：平仓与其开仓不在同一条record中时匹配不到lot，事件不带PnL/PnLPct/Duration。
+func (s *Server) publishTradeEvents(record *logger.DecisionRecord) {
+	if record == nil || len(record.Decisions) == 0 {
+		return
+	}
+	// 一条record可能含多个交易对的决策，每个交易对按各自的合约规格取整
+	seen := make(map[string]bool)
+	for _, decision := range record.Decisions {
+		symbol := strings.ToUpper(decision.Symbol)
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		instrument, _ := s.lookupInstrument(decision.Symbol)
+		events := buildTradeEvents([]*logger.DecisionRecord{record}, decision.Symbol, nil, nil, instrument)
+		for _, event := range events {
+			if s.hub != nil {
+				s.hub.publish("trade", event.Symbol, "", event)
+			}
+			s.notifyTradeEvent(event)
+		}
+	}
+}
+
+// publishKline 在上游行情更新时推送增量K线，供hub广播给订阅者
+func (s *Server) publishKline(symbol, interval string, k market.Kline) {
+	if s.hub == nil {
+		return
+	}
+	s.hub.publish("kline", symbol, interval, adaptKlines([]market.Kline{k})[0])
+}
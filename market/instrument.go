@@ -0,0 +1,110 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InstrumentInfo 描述交易对的精度与合约规格，字段命名参考goex的TickSize/FuturesContractInfo
+type InstrumentInfo struct {
+	InstrumentID   string  `json:"instrument_id"`
+	BaseCurrency   string  `json:"base_currency"`
+	QuoteCurrency  string  `json:"quote_currency"`
+	PriceTickSize  float64 `json:"price_tick_size"`
+	AmountTickSize float64 `json:"amount_tick_size"`
+	ContractValue  float64 `json:"contract_value"`
+	ContractType   string  `json:"contract_type"`
+	Delivery       string  `json:"delivery"`
+}
+
+var instrumentHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+type binanceExchangeInfo struct {
+	Symbols []struct {
+		Symbol     string `json:"symbol"`
+		BaseAsset  string `json:"baseAsset"`
+		QuoteAsset string `json:"quoteAsset"`
+		Filters    []struct {
+			FilterType string `json:"filterType"`
+			TickSize   string `json:"tickSize"`
+			StepSize   string `json:"stepSize"`
+		} `json:"filters"`
+	} `json:"symbols"`
+}
+
+// GetInstrumentInfo 拉取交易所的合约规格（tick size、步长等），供下单精度校验和事件金额取整使用
+func GetInstrumentInfo(symbol string) (InstrumentInfo, error) {
+	normalized := Normalize(symbol)
+
+	endpoint := fmt.Sprintf("https://fapi.binance.com/fapi/v1/exchangeInfo?symbol=%s", normalized)
+	resp, err := instrumentHTTPClient.Get(endpoint)
+	if err != nil {
+		return InstrumentInfo{}, fmt.Errorf("获取合约规格失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return InstrumentInfo{}, fmt.Errorf("合约规格接口返回错误状态码 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var parsed binanceExchangeInfo
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return InstrumentInfo{}, fmt.Errorf("解析合约规格响应失败: %w", err)
+	}
+	idx := -1
+	for i, candidate := range parsed.Symbols {
+		if strings.EqualFold(candidate.Symbol, normalized) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return InstrumentInfo{}, fmt.Errorf("未找到交易对 %s 的合约规格", normalized)
+	}
+
+	entry := parsed.Symbols[idx]
+	info := InstrumentInfo{
+		InstrumentID:  entry.Symbol,
+		BaseCurrency:  entry.BaseAsset,
+		QuoteCurrency: entry.QuoteAsset,
+		ContractType:  "perpetual",
+	}
+	for _, filter := range entry.Filters {
+		switch filter.FilterType {
+		case "PRICE_FILTER":
+			info.PriceTickSize = parseFloatOrZero(filter.TickSize)
+		case "LOT_SIZE", "MARKET_LOT_SIZE":
+			if info.AmountTickSize == 0 {
+				info.AmountTickSize = parseFloatOrZero(filter.StepSize)
+			}
+		}
+	}
+	if info.ContractValue == 0 {
+		info.ContractValue = 1
+	}
+
+	return info, nil
+}
+
+func parseFloatOrZero(value string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// RoundToTick 将value按tick粒度取整，tick<=0时原样返回，避免日志衍生事件出现不满足交易所精度的小数
+func RoundToTick(value, tick float64) float64 {
+	if tick <= 0 {
+		return value
+	}
+	return math.Round(value/tick) * tick
+}
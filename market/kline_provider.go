@@ -0,0 +1,328 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KlineProvider 是K线数据源的统一抽象，便于在多个交易所之间做路由和故障转移
+type KlineProvider interface {
+	// Name 返回数据源标识，用于路由配置和响应中的source字段
+	Name() string
+	// GetKlines 拉取K线，interval使用统一格式（如3m/5m/1h）
+	GetKlines(symbol, interval string, limit int) ([]Kline, error)
+}
+
+var klineHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// Normalize 把交易对写法统一成交易所惯用的大写永续合约代码（如"sol"->"SOLUSDT"），
+// 各Provider拉取行情和合约规格缓存key都经由它归一化；K线缓存key（api包的klineCacheKey）只统一大小写，不经过它
+func Normalize(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if strings.HasSuffix(symbol, "USDT") {
+		return symbol
+	}
+	return symbol + "USDT"
+}
+
+// NormalizeInterval 把各交易所的周期写法统一成 3m/5m/1h 这种简写
+func NormalizeInterval(interval string) string {
+	interval = strings.ToLower(strings.TrimSpace(interval))
+	switch interval {
+	case "1min":
+		return "1m"
+	case "3min":
+		return "3m"
+	case "5min":
+		return "5m"
+	case "15min":
+		return "15m"
+	case "30min":
+		return "30m"
+	case "60min", "1hour":
+		return "1h"
+	case "4hour":
+		return "4h"
+	case "1day":
+		return "1d"
+	default:
+		return interval
+	}
+}
+
+// ---- Binance ----
+
+// BinanceProvider 使用Binance合约REST接口获取K线
+type BinanceProvider struct{}
+
+func NewBinanceProvider() *BinanceProvider { return &BinanceProvider{} }
+
+func (p *BinanceProvider) Name() string { return "binance" }
+
+func (p *BinanceProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	normalized := Normalize(symbol)
+	endpoint := fmt.Sprintf("https://fapi.binance.com/fapi/v1/klines?symbol=%s&interval=%s&limit=%d",
+		normalized, NormalizeInterval(interval), limit)
+
+	resp, err := klineHTTPClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("binance获取K线失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("binance返回错误状态码 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var raw [][]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("解析binance K线响应失败: %w", err)
+	}
+
+	klines := make([]Kline, 0, len(raw))
+	for _, row := range raw {
+		if len(row) < 7 {
+			continue
+		}
+		klines = append(klines, Kline{
+			OpenTime:  int64ValueOf(row[0]),
+			Open:      floatValueOf(row[1]),
+			High:      floatValueOf(row[2]),
+			Low:       floatValueOf(row[3]),
+			Close:     floatValueOf(row[4]),
+			Volume:    floatValueOf(row[5]),
+			CloseTime: int64ValueOf(row[6]),
+		})
+	}
+	return klines, nil
+}
+
+// ---- Bybit ----
+
+// BybitProvider 使用Bybit v5 market/kline接口获取K线
+type BybitProvider struct{}
+
+func NewBybitProvider() *BybitProvider { return &BybitProvider{} }
+
+func (p *BybitProvider) Name() string { return "bybit" }
+
+func (p *BybitProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	normalized := Normalize(symbol)
+	endpoint := fmt.Sprintf("https://api.bybit.com/v5/market/kline?category=linear&symbol=%s&interval=%s&limit=%d",
+		normalized, bybitInterval(interval), limit)
+
+	resp, err := klineHTTPClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("bybit获取K线失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("bybit返回错误状态码 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var parsed struct {
+		Result struct {
+			List [][]string `json:"list"`
+		} `json:"result"`
+		RetMsg string `json:"retMsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("解析bybit K线响应失败: %w", err)
+	}
+	if parsed.RetMsg != "" && parsed.RetMsg != "OK" {
+		return nil, fmt.Errorf("bybit返回错误: %s", parsed.RetMsg)
+	}
+
+	// bybit按时间倒序返回，统一转换为正序并补齐close_time
+	intervalMs := intervalToMillis(interval)
+	klines := make([]Kline, 0, len(parsed.Result.List))
+	for i := len(parsed.Result.List) - 1; i >= 0; i-- {
+		row := parsed.Result.List[i]
+		if len(row) < 6 {
+			continue
+		}
+		openTime := parseInt64(row[0])
+		klines = append(klines, Kline{
+			OpenTime:  openTime,
+			Open:      parseFloatOrZero(row[1]),
+			High:      parseFloatOrZero(row[2]),
+			Low:       parseFloatOrZero(row[3]),
+			Close:     parseFloatOrZero(row[4]),
+			Volume:    parseFloatOrZero(row[5]),
+			CloseTime: openTime + intervalMs - 1,
+		})
+	}
+	return klines, nil
+}
+
+func bybitInterval(interval string) string {
+	switch NormalizeInterval(interval) {
+	case "1m":
+		return "1"
+	case "3m":
+		return "3"
+	case "5m":
+		return "5"
+	case "15m":
+		return "15"
+	case "30m":
+		return "30"
+	case "1h":
+		return "60"
+	case "4h":
+		return "240"
+	case "1d":
+		return "D"
+	default:
+		return "3"
+	}
+}
+
+// ---- OKX ----
+
+// OKXProvider 使用OKX市场行情candles接口获取K线
+type OKXProvider struct{}
+
+func NewOKXProvider() *OKXProvider { return &OKXProvider{} }
+
+func (p *OKXProvider) Name() string { return "okx" }
+
+func (p *OKXProvider) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	instID := okxInstID(symbol)
+	endpoint := fmt.Sprintf("https://www.okx.com/api/v5/market/candles?instId=%s&bar=%s&limit=%d",
+		instID, okxBar(interval), limit)
+
+	resp, err := klineHTTPClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("okx获取K线失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("okx返回错误状态码 %d: %s", resp.StatusCode, string(body))
+	}
+
+	var parsed struct {
+		Code string     `json:"code"`
+		Msg  string     `json:"msg"`
+		Data [][]string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("解析okx K线响应失败: %w", err)
+	}
+	if parsed.Code != "0" {
+		return nil, fmt.Errorf("okx返回错误: %s", parsed.Msg)
+	}
+
+	intervalMs := intervalToMillis(interval)
+	klines := make([]Kline, 0, len(parsed.Data))
+	for i := len(parsed.Data) - 1; i >= 0; i-- {
+		row := parsed.Data[i]
+		if len(row) < 6 {
+			continue
+		}
+		openTime := parseInt64(row[0])
+		klines = append(klines, Kline{
+			OpenTime:  openTime,
+			Open:      parseFloatOrZero(row[1]),
+			High:      parseFloatOrZero(row[2]),
+			Low:       parseFloatOrZero(row[3]),
+			Close:     parseFloatOrZero(row[4]),
+			Volume:    parseFloatOrZero(row[5]),
+			CloseTime: openTime + intervalMs - 1,
+		})
+	}
+	return klines, nil
+}
+
+func okxInstID(symbol string) string {
+	normalized := Normalize(symbol)
+	base := strings.TrimSuffix(normalized, "USDT")
+	return base + "-USDT-SWAP"
+}
+
+func okxBar(interval string) string {
+	switch NormalizeInterval(interval) {
+	case "1m":
+		return "1m"
+	case "3m":
+		return "3m"
+	case "5m":
+		return "5m"
+	case "15m":
+		return "15m"
+	case "30m":
+		return "30m"
+	case "1h":
+		return "1H"
+	case "4h":
+		return "4H"
+	case "1d":
+		return "1D"
+	default:
+		return "3m"
+	}
+}
+
+func intervalToMillis(interval string) int64 {
+	switch NormalizeInterval(interval) {
+	case "1m":
+		return 60_000
+	case "3m":
+		return 3 * 60_000
+	case "5m":
+		return 5 * 60_000
+	case "15m":
+		return 15 * 60_000
+	case "30m":
+		return 30 * 60_000
+	case "1h":
+		return 60 * 60_000
+	case "4h":
+		return 4 * 60 * 60_000
+	case "1d":
+		return 24 * 60 * 60_000
+	default:
+		return 3 * 60_000
+	}
+}
+
+func parseInt64(value string) int64 {
+	i, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+func int64ValueOf(value interface{}) int64 {
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	case json.Number:
+		i, _ := v.Int64()
+		return i
+	default:
+		return 0
+	}
+}
+
+func floatValueOf(value interface{}) float64 {
+	switch v := value.(type) {
+	case string:
+		return parseFloatOrZero(v)
+	case float64:
+		return v
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,105 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// LarkNotifier 通过飞书/Lark群机器人Webhook推送消息，支持签名校验
+type LarkNotifier struct {
+	webhookURL string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewLarkNotifier 创建Lark通知器，secret为空时不附带签名（对应未开启签名校验的机器人）
+func NewLarkNotifier(webhookURL, secret string) *LarkNotifier {
+	return &LarkNotifier{
+		webhookURL: webhookURL,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (n *LarkNotifier) Name() string { return "lark" }
+
+type larkCardPayload struct {
+	Timestamp string       `json:"timestamp,omitempty"`
+	Sign      string       `json:"sign,omitempty"`
+	MsgType   string       `json:"msg_type"`
+	Content   larkTextBody `json:"content"`
+}
+
+type larkTextBody struct {
+	Text string `json:"text"`
+}
+
+func (n *LarkNotifier) Notify(ctx context.Context, msg Message) error {
+	if n == nil || n.webhookURL == "" {
+		return fmt.Errorf("lark webhook未配置")
+	}
+
+	text := msg.Title
+	if msg.Text != "" {
+		text = text + "\n" + msg.Text
+	}
+	for k, v := range msg.Fields {
+		text += fmt.Sprintf("\n%s: %s", k, v)
+	}
+
+	payload := larkCardPayload{
+		MsgType: "text",
+		Content: larkTextBody{Text: text},
+	}
+
+	if n.secret != "" {
+		ts := time.Now().Unix()
+		sign, err := larkSign(ts, n.secret)
+		if err != nil {
+			return fmt.Errorf("生成lark签名失败: %w", err)
+		}
+		payload.Timestamp = strconv.FormatInt(ts, 10)
+		payload.Sign = sign
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化lark消息失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("推送lark消息失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("lark返回错误状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// larkSign 按lark机器人签名规则计算 base64(hmac_sha256(timestamp+"\n"+secret, ""))：
+// 以timestamp+"\n"+secret为key，对空消息做HMAC
+func larkSign(timestamp int64, secret string) (string, error) {
+	stringToSign := fmt.Sprintf("%d\n%s", timestamp, secret)
+	h := hmac.New(sha256.New, []byte(stringToSign))
+	if _, err := h.Write([]byte{}); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
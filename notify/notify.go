@@ -0,0 +1,18 @@
+// Package notify 提供AI信号与交易事件的外部推送能力
+package notify
+
+import "context"
+
+// Message 是推送给外部渠道的统一消息结构
+type Message struct {
+	Title string
+	Text  string
+	// Fields 是可选的键值对，用于卡片类渠道渲染结构化信息
+	Fields map[string]string
+}
+
+// Notifier 是推送渠道的统一接口，Lark/Slack兼容Webhook/通用HTTP POST均实现该接口
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
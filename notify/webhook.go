@@ -0,0 +1,99 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// WebhookNotifier 是通用HTTP POST渠道，Slack兼容的incoming-webhook也可直接复用
+type WebhookNotifier struct {
+	name       string
+	url        string
+	slackStyle bool
+	httpClient *http.Client
+}
+
+// NewWebhookNotifier 创建一个通用JSON Webhook通知器
+func NewWebhookNotifier(name, url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		name:       name,
+		url:        url,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// NewSlackWebhookNotifier 创建使用Slack incoming-webhook payload格式（{"text": "..."}）的通知器
+func NewSlackWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		name:       "slack",
+		url:        url,
+		slackStyle: true,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Name() string {
+	if n.name != "" {
+		return n.name
+	}
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	if n == nil || n.url == "" {
+		return fmt.Errorf("webhook地址未配置")
+	}
+
+	var body []byte
+	var err error
+	if n.slackStyle {
+		body, err = json.Marshal(map[string]string{"text": slackText(msg)})
+	} else {
+		body, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return fmt.Errorf("序列化webhook消息失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("推送webhook消息失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook返回错误状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// slackText 把Title/Text/Fields拼成Slack incoming-webhook的单段text，
+// 否则像交易事件这类把全部内容放在Fields里的消息会只剩一个空标题
+func slackText(msg Message) string {
+	text := msg.Title
+	if msg.Text != "" {
+		text += "\n" + msg.Text
+	}
+
+	keys := make([]string, 0, len(msg.Fields))
+	for k := range msg.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		text += fmt.Sprintf("\n%s: %s", k, msg.Fields[k])
+	}
+
+	return text
+}